@@ -1,12 +1,14 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -27,14 +29,115 @@ type admin struct {
 	adminChatID  int64
 	trainingMode bool
 	dry          bool
+
+	reportsMu   sync.Mutex
+	lastReports map[int64]time.Time // reporterID -> time of the last accepted /report, for rate-limiting
+
+	pendingReasonsMu sync.Mutex
+	pendingReasons   map[int]int64 // ForceReply prompt msgID -> userID awaiting a ban reason
+
+	banReasonsMu sync.Mutex
+	banReasons   map[int64]string // userID -> reason recorded for the last ban, surfaced in callbackShowInfo
+
+	settingsMu       sync.Mutex
+	settings         SettingsStore // optional, persists toggles so they survive restarts
+	detectionEnabled bool
+	softban          bool
+
+	auditMu     sync.Mutex
+	auditChatID int64 // optional chat receiving a structured, grep-friendly record of every ban/unban decision
+
+	historyMu sync.Mutex
+	history   map[int64]historyEntries // userID -> recent moderation actions, backs /history
+
+	externalBan *ExternalBanSource // optional, attached and started via UseExternalBanSource
+}
+
+// newAdmin creates an admin with its runtime toggles loaded from settings, if provided, so that
+// "/toggle training", "/toggle dry", "/toggle detection" and "/set softban on" all survive a restart
+// instead of resetting to the trainingMode/dry values passed in at construction.
+func newAdmin(tbAPI TbAPI, b Bot, locator Locator, superUsers SuperUsers, primChatID, adminChatID int64,
+	trainingMode, dry bool, settings SettingsStore) *admin {
+	a := &admin{
+		tbAPI:            tbAPI,
+		bot:              b,
+		locator:          locator,
+		superUsers:       superUsers,
+		primChatID:       primChatID,
+		adminChatID:      adminChatID,
+		trainingMode:     trainingMode,
+		dry:              dry,
+		settings:         settings,
+		detectionEnabled: true,
+	}
+	if settings != nil {
+		a.detectionEnabled = settings.GetBool("detection", true)
+		a.softban = settings.GetBool("softban", false)
+		a.trainingMode = settings.GetBool("training", trainingMode)
+		a.dry = settings.GetBool("dry", dry)
+	}
+	return a
+}
+
+// historyEntries is a bounded, append-only log of moderation actions for a single user
+type historyEntries []historyEntry
+
+// maxHistoryPerUser bounds how many past actions /history keeps per user
+const maxHistoryPerUser = 20
+
+// historyEntry is one recorded moderation decision, as surfaced by /history
+type historyEntry struct {
+	action   string
+	actor    string
+	duration time.Duration
+	reason   string
+	at       time.Time
+}
+
+// SettingsStore persists admin-chat toggles so they survive restarts.
+type SettingsStore interface {
+	SetBool(key string, value bool) error
+	GetBool(key string, defaultValue bool) bool
 }
 
 const (
 	confirmationPrefix = "?"
 	banPrefix          = "+"
 	infoPrefix         = "!"
+	durationPrefix     = "d"
+
+	reportCommand   = "report"
+	reportRateLimit = time.Minute
 )
 
+// banDurationOptions are the ban lengths offered to the admin once a ban is confirmed
+var banDurationOptions = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"permanent", bot.PermanentBanDuration},
+}
+
+// softBanDuration is the ban length applied instead of a permanent ban when the softban setting is on
+const softBanDuration = 24 * time.Hour
+
+// effectiveBanDuration downgrades a permanent ban to softBanDuration when softban is enabled. It is
+// only for the *implicit* default duration applied by an automatic ban (MsgHandler's forwarded-spam
+// ban, CheckAndBan's external-list ban); an admin explicitly picking "permanent" from askBanDuration's
+// menu is a deliberate override and must reach deleteAndBan untouched.
+func (a *admin) effectiveBanDuration(duration time.Duration) time.Duration {
+	a.settingsMu.Lock()
+	softban := a.softban
+	a.settingsMu.Unlock()
+	if softban && duration == bot.PermanentBanDuration {
+		return softBanDuration
+	}
+	return duration
+}
+
 // ReportBan a ban message to admin chat with a button to unban the user
 func (a *admin) ReportBan(banUserStr string, msg *bot.Message) {
 	log.Printf("[DEBUG] report to admin chat, ban msgsData for %s, group: %d", banUserStr, a.adminChatID)
@@ -43,26 +146,170 @@ func (a *admin) ReportBan(banUserStr string, msg *bot.Message) {
 	if err := a.sendWithUnbanMarkup(forwardMsg, "change ban", msg.From, msg.ID, a.adminChatID); err != nil {
 		log.Printf("[WARN] failed to send admin message, %v", err)
 	}
+	a.logAudit("ban", "auto", msg.From.ID, banUserStr, bot.PermanentBanDuration, "", msg.Text, a.spamChecks(msg.From.ID))
+}
+
+// HandleUpdate is the entry point the listener calls for every update received on the primary chat
+// (as opposed to MsgHandler/InlineCallbackHandler, which only ever see adminChatID traffic). today
+// that's routing a "/report" reply to ReportMessage and, if an external ban source is attached,
+// checking new joiners and message senders against it; everything else is left untouched for the
+// bot's normal spam-detection pipeline.
+func (a *admin) HandleUpdate(update tbapi.Update) error {
+	if update.Message == nil || update.Message.Chat.ID != a.primChatID {
+		return nil
+	}
+
+	if len(update.Message.NewChatMembers) > 0 {
+		return a.checkNewChatMembers(update.Message)
+	}
+
+	if update.Message.IsCommand() && update.Message.Command() == reportCommand {
+		return a.ReportMessage(update)
+	}
+
+	if a.externalBan != nil {
+		msg := bot.Message{ID: update.Message.MessageID, Text: update.Message.Text,
+			From: bot.User{ID: update.Message.From.ID, UserName: update.Message.From.UserName}}
+		if err := a.externalBan.CheckAndBan(msg); err != nil {
+			return fmt.Errorf("failed to check external ban list for %d: %w", update.Message.From.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// checkNewChatMembers runs every member who just joined the primary chat past the external ban list,
+// catching a listed user on arrival instead of waiting for their first message.
+func (a *admin) checkNewChatMembers(joinMsg *tbapi.Message) error {
+	if a.externalBan == nil {
+		return nil
+	}
+	errs := new(multierror.Error)
+	for _, member := range joinMsg.NewChatMembers {
+		msg := bot.Message{ID: joinMsg.MessageID, From: bot.User{ID: member.ID, UserName: member.UserName}}
+		if err := a.externalBan.CheckAndBan(msg); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to check external ban list for new member %d: %w", member.ID, err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// UseExternalBanSource attaches e to the admin and starts its background refresh loop: from this point
+// on, HandleUpdate consults e for every primary-chat message. The loop runs until ctx is canceled.
+func (a *admin) UseExternalBanSource(ctx context.Context, e *ExternalBanSource) {
+	a.externalBan = e
+	go func() {
+		if err := e.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("[WARN] external ban source stopped: %v", err)
+		}
+	}()
+}
+
+// ReportMessage handles a "/report" command sent by a regular chat member as a reply to a suspicious
+// message. It deletes the reporter's command and, unless rate-limited or refused, forwards the reported
+// message to adminChatID using the same sendWithUnbanMarkup flow as ReportBan.
+func (a *admin) ReportMessage(update tbapi.Update) error {
+	msg := update.Message
+	if !msg.IsCommand() || msg.Command() != reportCommand {
+		return fmt.Errorf("not a /%s command, ignored", reportCommand)
+	}
+	reporter := msg.From
+
+	if _, err := a.tbAPI.Request(tbapi.DeleteMessageConfig{ChatID: msg.Chat.ID, MessageID: msg.MessageID}); err != nil {
+		log.Printf("[WARN] failed to delete /report command %d: %v", msg.MessageID, err)
+	}
+
+	if msg.ReplyToMessage == nil {
+		return fmt.Errorf("/report from %s (%d) is not a reply, ignored", reporter.UserName, reporter.ID)
+	}
+
+	if !a.allowReport(reporter.ID) {
+		return fmt.Errorf("/report from %s (%d) rate-limited", reporter.UserName, reporter.ID)
+	}
+
+	reported := msg.ReplyToMessage
+	info, ok := a.locator.Message(reported.Text)
+	if !ok {
+		return fmt.Errorf("reported message not found in locator: %q", shrink(reported.Text, 50))
+	}
+
+	if info.UserName != "" && a.superUsers.IsSuper(info.UserName) {
+		return fmt.Errorf("report on super-user %s (%d) ignored", info.UserName, info.UserID)
+	}
+
+	if a.bot.IsApprovedUser(info.UserID) {
+		return fmt.Errorf("report on already approved user %s (%d) ignored", info.UserName, info.UserID)
+	}
+
+	log.Printf("[INFO] user %s (%d) reported message %d by %s (%d)", reporter.UserName, reporter.ID, info.MsgID, info.UserName, info.UserID)
+	text := strings.ReplaceAll(escapeMarkDownV1Text(reported.Text), "\n", " ")
+	forwardMsg := fmt.Sprintf("**reported by %s as spam, [%s](tg://user?id=%d)**\n\n%s\n\n",
+		escapeMarkDownV1Text(reporter.UserName), escapeMarkDownV1Text(info.UserName), info.UserID, text)
+	reportedUser := bot.User{ID: info.UserID, UserName: info.UserName}
+	if err := a.sendWithUnbanMarkup(forwardMsg, "change ban", reportedUser, info.MsgID, a.adminChatID); err != nil {
+		return fmt.Errorf("failed to send report to admin chat: %w", err)
+	}
+	return nil
+}
+
+// allowReport checks and updates the per-reporter rate limit, returning false if reporter should be throttled
+func (a *admin) allowReport(reporterID int64) bool {
+	a.reportsMu.Lock()
+	defer a.reportsMu.Unlock()
+	if a.lastReports == nil {
+		a.lastReports = map[int64]time.Time{}
+	}
+	if last, ok := a.lastReports[reporterID]; ok && time.Since(last) < reportRateLimit {
+		return false
+	}
+	a.lastReports[reporterID] = time.Now()
+	return true
+}
+
+// shrink truncates inp to at most max runes, adding an ellipsis if it was cut short
+func shrink(inp string, max int) string {
+	if utf8.RuneCountInString(inp) <= max {
+		return inp
+	}
+	return string([]rune(inp)[:max]) + "..."
 }
 
 // MsgHandler handles messages received on admin chat. this is usually forwarded spam failed
 // to be detected by the bot. we need to update spam filter with this message and ban the user.
 // the user will be baned even in training mode, but not in the dry mode.
 func (a *admin) MsgHandler(update tbapi.Update) error {
-	shrink := func(inp string, max int) string {
-		if utf8.RuneCountInString(inp) <= max {
-			return inp
-		}
-		return string([]rune(inp)[:max]) + "..."
-	}
 	log.Printf("[DEBUG] message from admin chat: msg id: %d, update id: %d, from: %s, sender: %q",
 		update.Message.MessageID, update.UpdateID, update.Message.From.UserName, update.Message.ForwardSenderName)
 
+	// a reply to a "reason for banning ..." ForceReply prompt, record it and stop. the ban itself
+	// was already logged without a reason (it happens before the prompt is even sent), so log a
+	// follow-up entry here with the real reason, which is what later /history and /whois lookups surface.
+	if update.Message.ReplyToMessage != nil {
+		if userID, ok := a.recordBanReason(update.Message.ReplyToMessage.MessageID, update.Message.Text); ok {
+			log.Printf("[INFO] ban reason for user %d recorded: %q", userID, update.Message.Text)
+			a.logAudit("reason", update.Message.From.UserName, userID, "", 0, update.Message.Text, "", a.spamChecks(userID))
+			return nil
+		}
+	}
+
+	// an admin-chat command from a superuser, e.g. "/settings", "/toggle training", "/whois @user"
+	if update.Message.IsCommand() {
+		return a.handleAdminCommand(update.Message)
+	}
+
 	if update.Message.ForwardSenderName == "" && update.Message.ForwardFrom == nil {
 		// this is a regular message from admin chat, not the forwarded one, ignore it
 		return nil
 	}
 
+	a.settingsMu.Lock()
+	detectionEnabled := a.detectionEnabled
+	a.settingsMu.Unlock()
+	if !detectionEnabled {
+		log.Printf("[DEBUG] detection disabled, ignoring forwarded message from %s", update.Message.From.UserName)
+		return nil
+	}
+
 	// this is a forwarded message from super to admin chat, it is an example of missed spam
 	// we need to update spam filter with this message
 	msgTxt := update.Message.Text
@@ -121,7 +368,7 @@ func (a *admin) MsgHandler(update tbapi.Update) error {
 	}
 
 	// ban user
-	banReq := banRequest{duration: bot.PermanentBanDuration, userID: info.UserID, chatID: a.primChatID,
+	banReq := banRequest{duration: a.effectiveBanDuration(bot.PermanentBanDuration), userID: info.UserID, chatID: a.primChatID,
 		tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode}
 
 	if err := banUserOrChannel(banReq); err != nil {
@@ -160,6 +407,15 @@ func (a *admin) InlineCallbackHandler(query *tbapi.CallbackQuery) error {
 		return nil
 	}
 
+	// if callback msgsData starts with "d", the admin picked a ban duration, ban the user for that long
+	if strings.HasPrefix(callbackData, durationPrefix) {
+		if err := a.callbackDurationChosen(query); err != nil {
+			return fmt.Errorf("failed to process ban duration: %w", err)
+		}
+		log.Printf("[DEBUG] ban duration chosen, chatID: %d, data: %s", chatID, callbackData)
+		return nil
+	}
+
 	// if callback msgsData starts with "!", we should show a spam info details
 	if strings.HasPrefix(callbackData, infoPrefix) {
 		if err := a.callbackShowInfo(query); err != nil {
@@ -230,17 +486,125 @@ func (a *admin) callbackBanConfirmed(query *tbapi.CallbackQuery) error {
 	if parseErr != nil {
 		return fmt.Errorf("failed to parse callback's userID %q: %w", query.Data, parseErr)
 	}
+	a.logAudit("kept", query.From.UserName, userID, "", 0, "", cleanMsg, a.spamChecks(userID))
 
-	if a.trainingMode {
-		// in training mode, the user is not banned automatically, here we do the real ban & delete the message
-		if err = a.deleteAndBan(query, userID, msgID); err != nil {
-			return fmt.Errorf("failed to ban user %d: %w", userID, err)
-		}
+	// always offer a choice of ban durations: in training mode this is the first real ban decision; outside
+	// training mode the user was already banned automatically before being reported, so this lets the admin change its length
+	if err = a.askBanDuration(query, userID, msgID); err != nil {
+		return fmt.Errorf("failed to ask ban duration for user %d: %w", userID, err)
 	}
 
 	return nil
 }
 
+// askBanDuration sends a follow-up message to adminChatID offering a choice of ban durations for userID/msgID.
+// the actual ban and message deletion happen once the admin picks one of the duration buttons.
+func (a *admin) askBanDuration(query *tbapi.CallbackQuery, userID int64, msgID int) error {
+	buttons := make([]tbapi.InlineKeyboardButton, 0, len(banDurationOptions))
+	for i, opt := range banDurationOptions {
+		data := fmt.Sprintf("%s%d:%d:%d", durationPrefix, i, userID, msgID)
+		buttons = append(buttons, tbapi.NewInlineKeyboardButtonData(opt.label, data))
+	}
+	durationMsg := tbapi.NewMessage(query.Message.Chat.ID, "pick a ban duration:")
+	durationMsg.ReplyMarkup = tbapi.NewInlineKeyboardMarkup(tbapi.NewInlineKeyboardRow(buttons...))
+	if err := send(durationMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to send duration choices, chatID:%d, %w", query.Message.Chat.ID, err)
+	}
+	return nil
+}
+
+// callbackDurationChosen handles the callback when the admin picks a ban duration.
+// it bans the user for the chosen duration, deletes the original message and asks for an optional reason.
+// callback data: d<optionIdx>:userID:msgID
+func (a *admin) callbackDurationChosen(query *tbapi.CallbackQuery) error {
+	optIdx, userID, msgID, err := a.parseDurationCallbackData(query.Data)
+	if err != nil {
+		return fmt.Errorf("failed to parse duration callback %q: %w", query.Data, err)
+	}
+
+	opt := banDurationOptions[optIdx]
+	if err := a.deleteAndBan(query, userID, msgID, opt.duration); err != nil {
+		return fmt.Errorf("failed to ban user %d for %s: %w", userID, opt.label, err)
+	}
+
+	reasonPrompt := tbapi.NewMessage(query.Message.Chat.ID,
+		fmt.Sprintf("reason for banning %d for %s? reply to this message, or ignore", userID, opt.label))
+	reasonPrompt.ReplyMarkup = tbapi.ForceReply{ForceReply: true}
+	sentMsg, err := a.tbAPI.Send(reasonPrompt)
+	if err != nil {
+		return fmt.Errorf("failed to ask for ban reason: %w", err)
+	}
+
+	a.pendingReasonsMu.Lock()
+	if a.pendingReasons == nil {
+		a.pendingReasons = map[int]int64{}
+	}
+	a.pendingReasons[sentMsg.MessageID] = userID
+	a.pendingReasonsMu.Unlock()
+
+	return nil
+}
+
+// takePendingReason returns and forgets the userID awaiting a ban reason for the given ForceReply prompt msgID
+func (a *admin) takePendingReason(promptMsgID int) (userID int64, ok bool) {
+	a.pendingReasonsMu.Lock()
+	defer a.pendingReasonsMu.Unlock()
+	userID, ok = a.pendingReasons[promptMsgID]
+	if ok {
+		delete(a.pendingReasons, promptMsgID)
+	}
+	return userID, ok
+}
+
+// recordBanReason stores reason for the userID awaiting a ban reason behind promptMsgID, returning the
+// userID and true if promptMsgID was a pending prompt. Split out from the ForceReply handling in
+// MsgHandler so the reason-capture bookkeeping can be exercised without a Telegram round-trip.
+func (a *admin) recordBanReason(promptMsgID int, reason string) (userID int64, ok bool) {
+	userID, ok = a.takePendingReason(promptMsgID)
+	if !ok {
+		return 0, false
+	}
+	a.banReasonsMu.Lock()
+	if a.banReasons == nil {
+		a.banReasons = map[int64]string{}
+	}
+	a.banReasons[userID] = reason
+	a.banReasonsMu.Unlock()
+	return userID, true
+}
+
+// banReason returns the recorded ban reason for userID, if any
+func (a *admin) banReason(userID int64) (string, bool) {
+	a.banReasonsMu.Lock()
+	defer a.banReasonsMu.Unlock()
+	reason, ok := a.banReasons[userID]
+	return reason, ok
+}
+
+// parseDurationCallbackData parses "d<optionIdx>:userID:msgID" callback data
+func (a *admin) parseDurationCallbackData(data string) (optIdx int, userID int64, msgID int, err error) {
+	if !strings.HasPrefix(data, durationPrefix) {
+		return 0, 0, 0, fmt.Errorf("unexpected duration callback data %q", data)
+	}
+	parts := strings.Split(data[1:], ":")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("unexpected duration callback data, want 3 parts %q", data)
+	}
+	if optIdx, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to parse option index %q: %w", parts[0], err)
+	}
+	if optIdx < 0 || optIdx >= len(banDurationOptions) {
+		return 0, 0, 0, fmt.Errorf("option index %d out of range", optIdx)
+	}
+	if userID, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to parse userID %q: %w", parts[1], err)
+	}
+	if msgID, err = strconv.Atoi(parts[2]); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to parse msgID %q: %w", parts[2], err)
+	}
+	return optIdx, userID, msgID, nil
+}
+
 // callbackUnbanConfirmed handles the callback when user unbanned.
 // it clears the keyboard and updates the message text with confirmation of unban.
 // also it unbans the user, adds it to the approved list and updates ham samples with the original message.
@@ -289,6 +653,7 @@ func (a *admin) callbackUnbanConfirmed(query *tbapi.CallbackQuery) error {
 	if err := a.bot.AddApprovedUser(userID, name); err != nil { // name is not available here
 		return fmt.Errorf("failed to add user %d to approved list: %w", userID, err)
 	}
+	a.logAudit("unban", query.From.UserName, userID, name, 0, "", cleanMsg, a.spamChecks(userID))
 
 	// Create the original forwarded message with new indication of "unbanned" and an empty keyboard
 	updText := query.Message.Text + fmt.Sprintf("\n\n_unbanned by %s in %v_",
@@ -320,6 +685,9 @@ func (a *admin) callbackShowInfo(query *tbapi.CallbackQuery) error {
 				spamInfo = append(spamInfo, "- "+escapeMarkDownV1Text(check.String()))
 			}
 		}
+		if reason, ok := a.banReason(userID); ok {
+			spamInfo = append(spamInfo, "- ban reason: "+escapeMarkDownV1Text(reason))
+		}
 		if len(spamInfo) > 0 {
 			spamInfoText = strings.Join(spamInfo, "\n")
 		}
@@ -340,11 +708,13 @@ func (a *admin) callbackShowInfo(query *tbapi.CallbackQuery) error {
 	return nil
 }
 
-// deleteAndBan deletes the message and bans the user
-func (a *admin) deleteAndBan(query *tbapi.CallbackQuery, userID int64, msgID int) error {
+// deleteAndBan deletes the message and bans the user for the given duration. duration is always an
+// explicit pick from askBanDuration's menu, so it bypasses effectiveBanDuration's softban downgrade:
+// an admin choosing "permanent" here means permanent, softban or not.
+func (a *admin) deleteAndBan(query *tbapi.CallbackQuery, userID int64, msgID int, duration time.Duration) error {
 	errs := new(multierror.Error)
 	banReq := banRequest{
-		duration: bot.PermanentBanDuration,
+		duration: duration,
 		userID:   userID,
 		chatID:   a.primChatID,
 		tbAPI:    a.tbAPI,
@@ -361,9 +731,11 @@ func (a *admin) deleteAndBan(query *tbapi.CallbackQuery, userID int64, msgID int
 		}
 	}
 
-	// we allow deleting messages from supers. This can be useful if super is training the bot by adding spam messages
+	// we allow deleting messages from supers. This can be useful if super is training the bot by adding spam messages.
+	// outside training mode the message may already be gone, deleted along with the automatic ban, so a
+	// failure here is logged but not fatal.
 	if _, err := a.tbAPI.Request(tbapi.DeleteMessageConfig{ChatID: a.primChatID, MessageID: msgID}); err != nil {
-		return fmt.Errorf("failed to delete message %d: %w", query.Message.MessageID, err)
+		log.Printf("[DEBUG] failed to delete message %d, it may already be gone: %v", msgID, err)
 	}
 
 	// any errors happened above will be returned
@@ -378,8 +750,11 @@ func (a *admin) deleteAndBan(query *tbapi.CallbackQuery, userID int64, msgID int
 
 	if msgFromSuper {
 		log.Printf("[INFO] message %d deleted, user %q (%d) is super, not banned", msgID, userName, userID)
+		a.logAudit("deleted", query.From.UserName, userID, userName, 0, "", query.Message.Text, a.spamChecks(userID))
 	} else {
 		log.Printf("[INFO] message %d deleted, user %q (%d) banned", msgID, userName, userID)
+		reason, _ := a.banReason(userID)
+		a.logAudit("ban", query.From.UserName, userID, userName, duration, reason, query.Message.Text, a.spamChecks(userID))
 	}
 	return nil
 }
@@ -480,3 +855,313 @@ func (a *admin) extractUsername(text string) (string, error) {
 
 	return "", errors.New("username not found")
 }
+
+// handleAdminCommand processes commands sent by a superuser in adminChatID: the runtime settings
+// toggles ("/settings", "/toggle <name>", "/set <name> <on|off>") and the lookup commands
+// ("/whois", "/find", "/history") used to reconstruct context before acting on a user.
+func (a *admin) handleAdminCommand(msg *tbapi.Message) error {
+	if !a.superUsers.IsSuper(msg.From.UserName) {
+		return fmt.Errorf("admin command %q from non-super user %s ignored", msg.Text, msg.From.UserName)
+	}
+
+	switch msg.Command() {
+	case "settings":
+		return a.replySettings(msg.Chat.ID)
+	case "toggle":
+		return a.toggleSetting(msg.Chat.ID, msg.CommandArguments())
+	case "set":
+		return a.setSetting(msg.Chat.ID, msg.CommandArguments())
+	case "whois":
+		return a.whoisCommand(msg.Chat.ID, msg.CommandArguments())
+	case "find":
+		return a.findCommand(msg.Chat.ID, msg.CommandArguments())
+	case "history":
+		return a.historyCommand(msg.Chat.ID, msg.CommandArguments())
+	default:
+		return nil // not an admin command we handle, ignore
+	}
+}
+
+// replySettings sends the current configuration to chatID
+func (a *admin) replySettings(chatID int64) error {
+	a.settingsMu.Lock()
+	text := fmt.Sprintf("**current settings**\n\n- training: `%v`\n- dry: `%v`\n- detection: `%v`\n- softban: `%v`",
+		a.trainingMode, a.dry, a.detectionEnabled, a.softban)
+	a.settingsMu.Unlock()
+
+	tbMsg := tbapi.NewMessage(chatID, text)
+	tbMsg.ParseMode = tbapi.ModeMarkdown
+	if err := send(tbMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to send settings, chatID:%d, %w", chatID, err)
+	}
+	return nil
+}
+
+// toggleSetting flips a boolean flag by name, persists it and confirms in chatID
+func (a *admin) toggleSetting(chatID int64, name string) error {
+	a.settingsMu.Lock()
+	var newVal bool
+	switch name {
+	case "training":
+		a.trainingMode = !a.trainingMode
+		newVal = a.trainingMode
+	case "dry":
+		a.dry = !a.dry
+		newVal = a.dry
+	case "detection":
+		a.detectionEnabled = !a.detectionEnabled
+		newVal = a.detectionEnabled
+	default:
+		a.settingsMu.Unlock()
+		return fmt.Errorf("unknown toggle %q", name)
+	}
+	a.settingsMu.Unlock()
+
+	a.persistSetting(name, newVal)
+	return a.confirmSetting(chatID, name, newVal)
+}
+
+// setSetting handles "/set <name> <on|off>", currently only the "softban" flag
+func (a *admin) setSetting(chatID int64, args string) error {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return fmt.Errorf("unexpected set arguments %q, want \"<name> <on|off>\"", args)
+	}
+	name, value := parts[0], parts[1]
+	if value != "on" && value != "off" {
+		return fmt.Errorf("unexpected set value %q, want \"on\" or \"off\"", value)
+	}
+	if name != "softban" {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	a.settingsMu.Lock()
+	a.softban = value == "on"
+	newVal := a.softban
+	a.settingsMu.Unlock()
+
+	a.persistSetting(name, newVal)
+	return a.confirmSetting(chatID, name, newVal)
+}
+
+// confirmSetting replies to chatID with the new value of a flipped setting
+func (a *admin) confirmSetting(chatID int64, name string, value bool) error {
+	tbMsg := tbapi.NewMessage(chatID, fmt.Sprintf("`%s` is now `%v`", name, value))
+	tbMsg.ParseMode = tbapi.ModeMarkdown
+	if err := send(tbMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to confirm %q, chatID:%d, %w", name, chatID, err)
+	}
+	return nil
+}
+
+// persistSetting saves a toggled value so it survives restarts, if a settings store is configured
+func (a *admin) persistSetting(name string, value bool) {
+	if a.settings == nil {
+		return
+	}
+	if err := a.settings.SetBool(name, value); err != nil {
+		log.Printf("[WARN] failed to persist setting %q: %v", name, err)
+	}
+}
+
+// spamChecks returns the stringified spam-detection results recorded for userID, if any, for inclusion
+// in a logAudit entry's checks= field.
+func (a *admin) spamChecks(userID int64) []string {
+	info, found := a.locator.Spam(userID)
+	if !found {
+		return nil
+	}
+	checks := make([]string, 0, len(info.Checks))
+	for _, check := range info.Checks {
+		checks = append(checks, check.String())
+	}
+	return checks
+}
+
+// logAudit emits a stable, grep-friendly record of a ban/unban decision to auditChatID, if configured.
+// if the send fails because the chat can no longer be found, auditing is auto-disabled and adminChatID
+// is notified, mirroring how other moderation bots handle a removed audit channel.
+func (a *admin) logAudit(action, actor string, userID int64, userName string, duration time.Duration, reason, excerpt string, checks []string) {
+	a.appendHistory(userID, action, actor, duration, reason)
+
+	a.auditMu.Lock()
+	chatID := a.auditChatID
+	a.auditMu.Unlock()
+	if chatID == 0 {
+		return
+	}
+
+	durStr := "n/a"
+	if duration > 0 {
+		durStr = duration.String()
+	}
+	if reason == "" {
+		reason = "n/a"
+	}
+	checksStr := "n/a"
+	if len(checks) > 0 {
+		checksStr = strings.Join(checks, ",")
+	}
+
+	text := fmt.Sprintf("`action=%s actor=%s target=%s(%d) duration=%s reason=%q checks=%s`\n%s",
+		action, actor, userName, userID, durStr, reason, checksStr, shrink(strings.ReplaceAll(excerpt, "\n", " "), 200))
+
+	if err := send(tbapi.NewMessage(chatID, text), a.tbAPI); err != nil {
+		log.Printf("[WARN] failed to send audit entry, chatID:%d, %v", chatID, err)
+		if strings.Contains(err.Error(), "chat not found") {
+			a.auditMu.Lock()
+			a.auditChatID = 0
+			a.auditMu.Unlock()
+			notice := tbapi.NewMessage(a.adminChatID, "**audit chat not found, audit logging disabled**")
+			notice.ParseMode = tbapi.ModeMarkdown
+			if nErr := send(notice, a.tbAPI); nErr != nil {
+				log.Printf("[WARN] failed to notify admin chat about disabled audit logging: %v", nErr)
+			}
+		}
+	}
+}
+
+// appendHistory records a moderation action for userID, trimming to the last maxHistoryPerUser entries
+func (a *admin) appendHistory(userID int64, action, actor string, duration time.Duration, reason string) {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	if a.history == nil {
+		a.history = map[int64]historyEntries{}
+	}
+	entries := append(a.history[userID], historyEntry{action: action, actor: actor, duration: duration, reason: reason, at: time.Now()})
+	if len(entries) > maxHistoryPerUser {
+		entries = entries[len(entries)-maxHistoryPerUser:]
+	}
+	a.history[userID] = entries
+}
+
+// userHistory returns a copy of the recorded moderation actions for userID, oldest first
+func (a *admin) userHistory(userID int64) historyEntries {
+	a.historyMu.Lock()
+	defer a.historyMu.Unlock()
+	entries := make(historyEntries, len(a.history[userID]))
+	copy(entries, a.history[userID])
+	return entries
+}
+
+// replyText sends a plain markdown-formatted message to chatID
+func (a *admin) replyText(chatID int64, text string) error {
+	tbMsg := tbapi.NewMessage(chatID, text)
+	tbMsg.ParseMode = tbapi.ModeMarkdown
+	if err := send(tbMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to send message, chatID:%d, %w", chatID, err)
+	}
+	return nil
+}
+
+// resolveUserID turns a "/whois"-style argument, either a numeric userID or an "@username", into a userID
+func (a *admin) resolveUserID(input string) (int64, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, errors.New("empty user reference")
+	}
+
+	if strings.HasPrefix(input, "@") {
+		userID, ok := a.locator.UserIDByName(strings.TrimPrefix(input, "@"))
+		if !ok {
+			return 0, fmt.Errorf("user %s not found", input)
+		}
+		return userID, nil
+	}
+
+	userID, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse userID %q: %w", input, err)
+	}
+	return userID, nil
+}
+
+// orNA returns s, or "n/a" if s is empty
+func orNA(s string) string {
+	if s == "" {
+		return "n/a"
+	}
+	return s
+}
+
+// whoisCommand handles "/whois <userID|@username>": approved status, last known name, the last
+// recorded moderation action and the most recent spam detection results for the user.
+func (a *admin) whoisCommand(chatID int64, args string) error {
+	userID, err := a.resolveUserID(args)
+	if err != nil {
+		return fmt.Errorf("whois: %w", err)
+	}
+
+	userName := a.locator.UserNameByID(userID)
+	lines := []string{
+		fmt.Sprintf("**whois %d**", userID),
+		fmt.Sprintf("- name: %s", orNA(userName)),
+		fmt.Sprintf("- approved: %v", a.bot.IsApprovedUser(userID)),
+	}
+
+	member, mErr := a.tbAPI.GetChatMember(tbapi.GetChatMemberConfig{
+		ChatConfigWithUser: tbapi.ChatConfigWithUser{ChatID: a.primChatID, UserID: userID}})
+	if mErr != nil {
+		log.Printf("[DEBUG] failed to get chat member status for %d: %v", userID, mErr)
+		lines = append(lines, "- status: unknown")
+	} else {
+		lines = append(lines, fmt.Sprintf("- status: %s", member.Status))
+	}
+
+	if entries := a.userHistory(userID); len(entries) > 0 {
+		last := entries[len(entries)-1]
+		lines = append(lines, fmt.Sprintf("- last action: %s by %s, reason: %s, %v ago",
+			last.action, last.actor, orNA(last.reason), time.Since(last.at).Round(time.Second)))
+	} else {
+		lines = append(lines, "- last action: n/a")
+	}
+
+	if info, found := a.locator.Spam(userID); found {
+		lines = append(lines, "**last known spam detection**")
+		for _, check := range info.Checks {
+			lines = append(lines, "- "+escapeMarkDownV1Text(check.String()))
+		}
+	}
+
+	return a.replyText(chatID, strings.Join(lines, "\n"))
+}
+
+// findCommand handles "/find <substring>": searches the locator's message samples for a substring match
+func (a *admin) findCommand(chatID int64, args string) error {
+	substr := strings.TrimSpace(args)
+	if substr == "" {
+		return errors.New("find: empty search string")
+	}
+
+	matches := a.locator.Find(substr)
+	if len(matches) == 0 {
+		return a.replyText(chatID, fmt.Sprintf("no messages matching %q found", substr))
+	}
+
+	lines := []string{fmt.Sprintf("**%d matches for %q**", len(matches), substr)}
+	for _, m := range matches {
+		lines = append(lines, fmt.Sprintf("- %s (%d), msg %d", orNA(m.UserName), m.UserID, m.MsgID))
+	}
+	return a.replyText(chatID, strings.Join(lines, "\n"))
+}
+
+// historyCommand handles "/history <userID|@username>": lists the recorded moderation actions for the user
+func (a *admin) historyCommand(chatID int64, args string) error {
+	userID, err := a.resolveUserID(args)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+
+	entries := a.userHistory(userID)
+	if len(entries) == 0 {
+		return a.replyText(chatID, fmt.Sprintf("no moderation history for %d", userID))
+	}
+
+	lines := []string{fmt.Sprintf("**moderation history for %d**", userID)}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		lines = append(lines, fmt.Sprintf("- %s: %s by %s (%v ago), reason: %s",
+			e.at.Format(time.RFC3339), e.action, e.actor, time.Since(e.at).Round(time.Second), orNA(e.reason)))
+	}
+	return a.replyText(chatID, strings.Join(lines, "\n"))
+}
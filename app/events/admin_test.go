@@ -0,0 +1,468 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/umputun/tg-spam/app/bot"
+)
+
+// fakeTbAPI is a minimal TbAPI double recording what it was asked to send/request
+type fakeTbAPI struct {
+	sent      []tbapi.Chattable
+	requested []tbapi.Chattable
+	sendErr   error
+	member    tbapi.ChatMember
+	memberErr error
+}
+
+func (f *fakeTbAPI) Send(c tbapi.Chattable) (tbapi.Message, error) {
+	f.sent = append(f.sent, c)
+	if f.sendErr != nil {
+		return tbapi.Message{}, f.sendErr
+	}
+	return tbapi.Message{MessageID: len(f.sent)}, nil
+}
+
+func (f *fakeTbAPI) Request(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+	f.requested = append(f.requested, c)
+	return &tbapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeTbAPI) GetChatMember(tbapi.GetChatMemberConfig) (tbapi.ChatMember, error) {
+	return f.member, f.memberErr
+}
+
+func (f *fakeTbAPI) GetUpdatesChan(tbapi.UpdateConfig) tbapi.UpdatesChannel {
+	return make(tbapi.UpdatesChannel)
+}
+
+// fakeBot is a minimal Bot double tracking the approved-user list
+type fakeBot struct {
+	approved map[int64]bool
+	resp     bot.Response
+}
+
+func newFakeBot() *fakeBot { return &fakeBot{approved: map[int64]bool{}} }
+
+func (f *fakeBot) IsApprovedUser(id int64) bool             { return f.approved[id] }
+func (f *fakeBot) AddApprovedUser(id int64, _ string) error { f.approved[id] = true; return nil }
+func (f *fakeBot) RemoveApprovedUser(id int64) error        { delete(f.approved, id); return nil }
+func (f *fakeBot) OnMessage(bot.Message) bot.Response       { return f.resp }
+func (f *fakeBot) UpdateHam(string) error                   { return nil }
+func (f *fakeBot) UpdateSpam(string) error                  { return nil }
+
+// fakeSuperUsers is a set-backed SuperUsers double
+type fakeSuperUsers map[string]bool
+
+func (f fakeSuperUsers) IsSuper(name string) bool { return f[name] }
+
+// fakeLocator is a map-backed Locator double
+type fakeLocator struct {
+	messages map[string]LocatorUserInfo
+	spam     map[int64]LocatorSpamInfo
+	names    map[int64]string
+	ids      map[string]int64
+	found    []LocatorUserInfo
+}
+
+func newFakeLocator() *fakeLocator {
+	return &fakeLocator{
+		messages: map[string]LocatorUserInfo{},
+		spam:     map[int64]LocatorSpamInfo{},
+		names:    map[int64]string{},
+		ids:      map[string]int64{},
+	}
+}
+
+func (f *fakeLocator) Message(msg string) (LocatorUserInfo, bool) {
+	i, ok := f.messages[msg]
+	return i, ok
+}
+func (f *fakeLocator) Spam(userID int64) (LocatorSpamInfo, bool) {
+	i, ok := f.spam[userID]
+	return i, ok
+}
+func (f *fakeLocator) UserNameByID(userID int64) string       { return f.names[userID] }
+func (f *fakeLocator) UserIDByName(name string) (int64, bool) { id, ok := f.ids[name]; return id, ok }
+func (f *fakeLocator) Find(string) []LocatorUserInfo          { return f.found }
+
+// fakeSettings is a map-backed SettingsStore double
+type fakeSettings struct{ vals map[string]bool }
+
+func newFakeSettings() *fakeSettings { return &fakeSettings{vals: map[string]bool{}} }
+
+func (f *fakeSettings) SetBool(key string, value bool) error { f.vals[key] = value; return nil }
+func (f *fakeSettings) GetBool(key string, defaultValue bool) bool {
+	if v, ok := f.vals[key]; ok {
+		return v
+	}
+	return defaultValue
+}
+
+func newTestAdmin() (*admin, *fakeTbAPI, *fakeBot, fakeSuperUsers, *fakeLocator, *fakeSettings) {
+	tb := &fakeTbAPI{}
+	b := newFakeBot()
+	su := fakeSuperUsers{"boss": true}
+	loc := newFakeLocator()
+	settings := newFakeSettings()
+	a := newAdmin(tb, b, loc, su, 1, 2, false, false, settings)
+	return a, tb, b, su, loc, settings
+}
+
+// reportCommandMessage builds a *tbapi.Message with a recognized "/report" command entity
+func reportCommandMessage(chatID int64, reporter tbapi.User, replyTo *tbapi.Message) *tbapi.Message {
+	return &tbapi.Message{
+		Chat:           &tbapi.Chat{ID: chatID},
+		From:           &reporter,
+		Text:           "/report",
+		Entities:       []tbapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 7}},
+		ReplyToMessage: replyTo,
+	}
+}
+
+func TestAdminRecordBanReason(t *testing.T) {
+	a := &admin{}
+	a.pendingReasonsMu.Lock()
+	a.pendingReasons = map[int]int64{42: 100}
+	a.pendingReasonsMu.Unlock()
+
+	userID, ok := a.recordBanReason(42, "spam link")
+	if !ok || userID != 100 {
+		t.Fatalf("expected (100, true), got (%d, %v)", userID, ok)
+	}
+
+	reason, ok := a.banReason(100)
+	if !ok || reason != "spam link" {
+		t.Fatalf("expected the recorded reason to be readable, got (%q, %v)", reason, ok)
+	}
+
+	if _, ok := a.recordBanReason(42, "again"); ok {
+		t.Fatal("a prompt msgID should only be consumable once")
+	}
+}
+
+func TestAdminRecordBanReasonUnknownPrompt(t *testing.T) {
+	a := &admin{}
+	if _, ok := a.recordBanReason(1, "whatever"); ok {
+		t.Fatal("expected no match for an unrecorded prompt msgID")
+	}
+}
+
+func TestAdminAppendHistoryTrimsToMax(t *testing.T) {
+	a := &admin{}
+	for i := 0; i < maxHistoryPerUser+5; i++ {
+		a.appendHistory(1, "ban", "tester", 0, "")
+	}
+
+	entries := a.userHistory(1)
+	if len(entries) != maxHistoryPerUser {
+		t.Fatalf("expected history capped at %d entries, got %d", maxHistoryPerUser, len(entries))
+	}
+}
+
+func TestAdminEffectiveBanDuration(t *testing.T) {
+	a := &admin{}
+	if got := a.effectiveBanDuration(bot.PermanentBanDuration); got != bot.PermanentBanDuration {
+		t.Fatalf("expected permanent ban duration unchanged when softban is off, got %v", got)
+	}
+
+	a.softban = true
+	if got := a.effectiveBanDuration(bot.PermanentBanDuration); got != softBanDuration {
+		t.Fatalf("expected softban duration %v, got %v", softBanDuration, got)
+	}
+	if got := a.effectiveBanDuration(time.Hour); got != time.Hour {
+		t.Fatalf("expected an explicitly chosen duration to be left untouched, got %v", got)
+	}
+}
+
+func TestAdminReportMessageHappyPath(t *testing.T) {
+	a, tb, _, _, loc, _ := newTestAdmin()
+	reported := &tbapi.Message{MessageID: 10, Text: "buy cheap followers"}
+	loc.messages[reported.Text] = LocatorUserInfo{UserID: 200, UserName: "spammer", MsgID: 10}
+
+	msg := reportCommandMessage(1, tbapi.User{ID: 1, UserName: "reporter"}, reported)
+	if err := a.ReportMessage(tbapi.Update{Message: msg}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected the report forwarded to admin chat, got %d sent messages", len(tb.sent))
+	}
+}
+
+func TestAdminReportMessageRejectsNonReply(t *testing.T) {
+	a, _, _, _, _, _ := newTestAdmin()
+	msg := reportCommandMessage(1, tbapi.User{ID: 1, UserName: "reporter"}, nil)
+	if err := a.ReportMessage(tbapi.Update{Message: msg}); err == nil {
+		t.Fatal("expected an error for a /report that isn't a reply")
+	}
+}
+
+func TestAdminReportMessageRateLimited(t *testing.T) {
+	a, _, _, _, loc, _ := newTestAdmin()
+	reported := &tbapi.Message{MessageID: 10, Text: "buy cheap followers"}
+	loc.messages[reported.Text] = LocatorUserInfo{UserID: 200, UserName: "spammer", MsgID: 10}
+
+	reporter := tbapi.User{ID: 1, UserName: "reporter"}
+	if err := a.ReportMessage(tbapi.Update{Message: reportCommandMessage(1, reporter, reported)}); err != nil {
+		t.Fatalf("unexpected error on first report: %v", err)
+	}
+	if err := a.ReportMessage(tbapi.Update{Message: reportCommandMessage(1, reporter, reported)}); err == nil {
+		t.Fatal("expected the second /report from the same reporter to be rate-limited")
+	}
+}
+
+func TestAdminReportMessageIgnoresSuperUserAndApproved(t *testing.T) {
+	a, _, _, _, loc, _ := newTestAdmin()
+	reported := &tbapi.Message{MessageID: 10, Text: "msg from boss"}
+	loc.messages[reported.Text] = LocatorUserInfo{UserID: 100, UserName: "boss", MsgID: 10}
+	if err := a.ReportMessage(tbapi.Update{Message: reportCommandMessage(1, tbapi.User{ID: 1, UserName: "reporter"}, reported)}); err == nil {
+		t.Fatal("expected a report on a super-user to be ignored")
+	}
+
+	a2, _, b2, _, loc2, _ := newTestAdmin()
+	b2.approved[300] = true
+	reported2 := &tbapi.Message{MessageID: 11, Text: "approved user msg"}
+	loc2.messages[reported2.Text] = LocatorUserInfo{UserID: 300, UserName: "regular", MsgID: 11}
+	if err := a2.ReportMessage(tbapi.Update{Message: reportCommandMessage(1, tbapi.User{ID: 2, UserName: "reporter2"}, reported2)}); err == nil {
+		t.Fatal("expected a report on an already approved user to be ignored")
+	}
+}
+
+func TestAdminHandleUpdateRoutesReportAndIgnoresOtherChats(t *testing.T) {
+	a, tb, _, _, loc, _ := newTestAdmin()
+	a.primChatID = 1
+	reported := &tbapi.Message{MessageID: 10, Text: "buy cheap followers"}
+	loc.messages[reported.Text] = LocatorUserInfo{UserID: 200, UserName: "spammer", MsgID: 10}
+
+	update := tbapi.Update{Message: reportCommandMessage(1, tbapi.User{ID: 1, UserName: "reporter"}, reported)}
+	if err := a.HandleUpdate(update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected the /report to be routed and forwarded, got %d sent messages", len(tb.sent))
+	}
+
+	other := tbapi.Update{Message: &tbapi.Message{Chat: &tbapi.Chat{ID: 999}, Text: "hello"}}
+	if err := a.HandleUpdate(other); err != nil {
+		t.Fatalf("unexpected error for a message outside primChatID: %v", err)
+	}
+}
+
+// TestAdminHandleUpdateBansNewChatMembersFoundOnExternalBanList checks that a listed user is caught on
+// arrival, not just on their first message.
+func TestAdminHandleUpdateBansNewChatMembersFoundOnExternalBanList(t *testing.T) {
+	a, tb, _, _, _, _ := newTestAdmin()
+	a.primChatID = 1
+	e := NewExternalBanSource(a, a.primChatID, time.Hour, false, NewCASBanListSource("https://example.com"))
+	e.cache[42] = "known spammer"
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.UseExternalBanSource(ctx, e)
+
+	update := tbapi.Update{Message: &tbapi.Message{
+		Chat:           &tbapi.Chat{ID: 1},
+		NewChatMembers: []tbapi.User{{ID: 42, UserName: "spammer"}, {ID: 43, UserName: "regular"}},
+	}}
+	if err := a.HandleUpdate(update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.requested) != 1 {
+		t.Fatalf("expected the listed joiner to be banned, got %d ban requests", len(tb.requested))
+	}
+}
+
+func TestAdminDeleteAndBanSkipsSuperUser(t *testing.T) {
+	a, _, _, _, loc, _ := newTestAdmin()
+	loc.names[100] = "boss"
+	query := &tbapi.CallbackQuery{From: &tbapi.User{UserName: "admin"}, Message: &tbapi.Message{Text: "some message"}}
+
+	if err := a.deleteAndBan(query, 100, 5, bot.PermanentBanDuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := a.userHistory(100)
+	if len(entries) != 1 || entries[0].action != "deleted" {
+		t.Fatalf("expected a recorded 'deleted' action and no ban for a super-user, got %+v", entries)
+	}
+}
+
+func TestAdminDeleteAndBanKeepsExplicitPermanentUnderSoftban(t *testing.T) {
+	a, _, _, _, loc, _ := newTestAdmin()
+	a.softban = true
+	loc.names[200] = "spammer"
+	query := &tbapi.CallbackQuery{From: &tbapi.User{UserName: "admin"}, Message: &tbapi.Message{Text: "some message"}}
+
+	if err := a.deleteAndBan(query, 200, 5, bot.PermanentBanDuration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := a.userHistory(200)
+	if len(entries) != 1 || entries[0].duration != bot.PermanentBanDuration {
+		t.Fatalf("expected an explicit permanent pick to bypass softban's downgrade, got %+v", entries)
+	}
+}
+
+func TestAdminNewAdminLoadsTrainingAndDryFromSettings(t *testing.T) {
+	settings := newFakeSettings()
+	settings.vals["training"] = true
+	settings.vals["dry"] = true
+	a := newAdmin(&fakeTbAPI{}, newFakeBot(), newFakeLocator(), fakeSuperUsers{}, 1, 2, false, false, settings)
+	if !a.trainingMode || !a.dry {
+		t.Fatalf("expected training and dry restored from settings, got training=%v dry=%v", a.trainingMode, a.dry)
+	}
+
+	// with no settings store, constructor args are used as-is
+	a2 := newAdmin(&fakeTbAPI{}, newFakeBot(), newFakeLocator(), fakeSuperUsers{}, 1, 2, true, true, nil)
+	if !a2.trainingMode || !a2.dry {
+		t.Fatalf("expected constructor args kept with no settings store, got training=%v dry=%v", a2.trainingMode, a2.dry)
+	}
+}
+
+func TestAdminToggleSetting(t *testing.T) {
+	a, tb, _, _, _, settings := newTestAdmin()
+
+	if err := a.toggleSetting(2, "training"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !a.trainingMode {
+		t.Fatal("expected training mode toggled on")
+	}
+	if !settings.vals["training"] {
+		t.Fatal("expected training toggle persisted")
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected one confirmation message, got %d", len(tb.sent))
+	}
+
+	if err := a.toggleSetting(2, "nonsense"); err == nil {
+		t.Fatal("expected an error for an unknown toggle name")
+	}
+}
+
+func TestAdminSetSettingSoftban(t *testing.T) {
+	a, _, _, _, _, settings := newTestAdmin()
+
+	if err := a.setSetting(2, "softban on"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !a.softban || !settings.vals["softban"] {
+		t.Fatal("expected softban turned on and persisted")
+	}
+
+	if err := a.setSetting(2, "softban maybe"); err == nil {
+		t.Fatal("expected an error for a non on/off value")
+	}
+	if err := a.setSetting(2, "training on"); err == nil {
+		t.Fatal("expected an error for a setting other than softban")
+	}
+	if err := a.setSetting(2, "softban"); err == nil {
+		t.Fatal("expected an error for malformed arguments")
+	}
+}
+
+func TestAdminHandleAdminCommandRejectsNonSuper(t *testing.T) {
+	a, _, _, _, _, _ := newTestAdmin()
+	msg := &tbapi.Message{Text: "/settings", From: &tbapi.User{UserName: "stranger"},
+		Entities: []tbapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: 9}}}
+	if err := a.handleAdminCommand(msg); err == nil {
+		t.Fatal("expected admin command from a non-super user to be rejected")
+	}
+}
+
+func TestAdminLogAuditIncludesSpamChecks(t *testing.T) {
+	a, tb, _, _, loc, _ := newTestAdmin()
+	a.auditChatID = 3
+	loc.spam[100] = LocatorSpamInfo{Checks: []fmt.Stringer{stubCheck("stopword"), stubCheck("ml-spam")}}
+
+	a.logAudit("ban", "boss", 100, "spammer", time.Hour, "reason", "excerpt", a.spamChecks(100))
+
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected one audit message sent, got %d", len(tb.sent))
+	}
+	sent, ok := tb.sent[0].(tbapi.MessageConfig)
+	if !ok {
+		t.Fatalf("expected a MessageConfig, got %T", tb.sent[0])
+	}
+	if !strings.Contains(sent.Text, "checks=stopword,ml-spam") {
+		t.Fatalf("expected audit text to include both checks, got %q", sent.Text)
+	}
+}
+
+func TestAdminLogAuditChatNotFoundDisablesAuditing(t *testing.T) {
+	a, tb, _, _, _, _ := newTestAdmin()
+	a.adminChatID = 2
+	a.auditChatID = 3
+	tb.sendErr = errors.New("Bad Request: chat not found")
+
+	a.logAudit("ban", "boss", 100, "spammer", time.Hour, "reason", "excerpt", nil)
+
+	if a.auditChatID != 0 {
+		t.Fatal("expected auditChatID to be cleared after a chat-not-found send failure")
+	}
+}
+
+func TestAdminWhoisCommand(t *testing.T) {
+	a, tb, _, _, loc, _ := newTestAdmin()
+	loc.names[100] = "spammer"
+	loc.ids["spammer"] = 100
+	loc.spam[100] = LocatorSpamInfo{Checks: []fmt.Stringer{stubCheck("stopword")}}
+	a.appendHistory(100, "ban", "boss", time.Hour, "spam link")
+
+	if err := a.whoisCommand(2, "@spammer"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected one reply, got %d", len(tb.sent))
+	}
+
+	if err := a.whoisCommand(2, "@ghost"); err == nil {
+		t.Fatal("expected an error resolving an unknown username")
+	}
+}
+
+func TestAdminFindCommand(t *testing.T) {
+	a, tb, _, _, loc, _ := newTestAdmin()
+	loc.found = []LocatorUserInfo{{UserID: 1, UserName: "spammer", MsgID: 5}}
+
+	if err := a.findCommand(2, "buy now"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected one reply, got %d", len(tb.sent))
+	}
+
+	if err := a.findCommand(2, "   "); err == nil {
+		t.Fatal("expected an error for an empty search string")
+	}
+}
+
+func TestAdminHistoryCommand(t *testing.T) {
+	a, tb, _, _, _, _ := newTestAdmin()
+
+	if err := a.historyCommand(2, "100"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 1 {
+		t.Fatalf("expected a reply even with no history, got %d", len(tb.sent))
+	}
+
+	a.appendHistory(100, "ban", "boss", time.Hour, "spam link")
+	if err := a.historyCommand(2, "100"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tb.sent) != 2 {
+		t.Fatalf("expected a second reply, got %d", len(tb.sent))
+	}
+}
+
+// stubCheck is a minimal fmt.Stringer standing in for bot.CheckResult in tests
+type stubCheck string
+
+func (s stubCheck) String() string { return string(s) }
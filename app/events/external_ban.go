@@ -0,0 +1,248 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/umputun/tg-spam/app/bot"
+)
+
+// BanListSource is a pluggable source of an externally-curated ban list, e.g. CAS or SpamWatch.
+// Parse turns the raw response body into a map of userID -> reason.
+type BanListSource interface {
+	URL() string
+	Parse(data []byte) (map[int64]string, error)
+}
+
+// ExternalBanSource periodically pulls known-abusive Telegram user IDs from one or more BanListSource
+// feeds, caches them and exposes IsBanned for the listener's message pipeline to consult. New joiners
+// and first messages from a listed ID are auto-banned via the same banUserOrChannel path used from
+// the admin chat, with an informational note posted to adminChatID so admins can still unban.
+type ExternalBanSource struct {
+	sources    []BanListSource
+	refresh    time.Duration
+	httpClient *http.Client
+	admin      *admin
+	primChatID int64
+	dry        bool
+
+	mu      sync.RWMutex
+	cache   map[int64]string // userID -> reason
+	cacheMu sync.Mutex       // guards meta below, separate from the read-heavy cache lock
+	meta    map[string]sourceMeta
+
+	actionedMu sync.Mutex
+	actioned   map[int64]bool // userID -> already banned/notified, so a dry-mode repeat poster isn't re-announced
+}
+
+// sourceMeta tracks the caching headers returned by a source, to be polite to upstream on refresh
+type sourceMeta struct {
+	etag         string
+	lastModified string
+}
+
+// NewExternalBanSource creates an ExternalBanSource polling sources every refresh interval.
+// admin is used to post the "auto-banned" notice with the standard unban markup.
+func NewExternalBanSource(admin *admin, primChatID int64, refresh time.Duration, dry bool, sources ...BanListSource) *ExternalBanSource {
+	return &ExternalBanSource{
+		sources:    sources,
+		refresh:    refresh,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		admin:      admin,
+		primChatID: primChatID,
+		dry:        dry,
+		cache:      map[int64]string{},
+		meta:       map[string]sourceMeta{},
+		actioned:   map[int64]bool{},
+	}
+}
+
+// Run polls all sources every refresh interval until ctx is canceled. The first refresh happens
+// immediately so the cache is warm before the first message is processed.
+func (e *ExternalBanSource) Run(ctx context.Context) error {
+	if err := e.refreshAll(); err != nil {
+		log.Printf("[WARN] initial external ban list refresh failed: %v", err)
+	}
+
+	ticker := time.NewTicker(e.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.refreshAll(); err != nil {
+				log.Printf("[WARN] external ban list refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// refreshAll fetches every configured source and merges the results into the cache
+func (e *ExternalBanSource) refreshAll() error {
+	merged := map[int64]string{}
+	var lastErr error
+	for _, src := range e.sources {
+		entries, err := e.refreshOne(src)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to refresh %s: %w", src.URL(), err)
+			log.Printf("[WARN] %v", lastErr)
+			continue
+		}
+		for userID, reason := range entries {
+			merged[userID] = reason
+		}
+	}
+
+	e.mu.Lock()
+	for userID, reason := range merged {
+		e.cache[userID] = reason
+	}
+	cached := len(e.cache)
+	e.mu.Unlock()
+
+	log.Printf("[INFO] external ban list refreshed, %d users cached", cached)
+	return lastErr
+}
+
+// refreshOne fetches and parses a single source, skipping the parse if the upstream reports
+// the cached copy is still fresh (304 Not Modified).
+func (e *ExternalBanSource) refreshOne(src BanListSource) (map[int64]string, error) {
+	req, err := http.NewRequest(http.MethodGet, src.URL(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	e.cacheMu.Lock()
+	meta := e.meta[src.URL()]
+	e.cacheMu.Unlock()
+	if meta.etag != "" {
+		req.Header.Set("If-None-Match", meta.etag)
+	}
+	if meta.lastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.lastModified)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	entries, err := src.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	e.cacheMu.Lock()
+	e.meta[src.URL()] = sourceMeta{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	e.cacheMu.Unlock()
+
+	return entries, nil
+}
+
+// IsBanned reports whether userID is present on one of the cached external ban lists
+func (e *ExternalBanSource) IsBanned(userID int64) (reason string, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	reason, ok = e.cache[userID]
+	return reason, ok
+}
+
+// CheckAndBan bans msg.From if they appear on a cached external ban list, via the same
+// banUserOrChannel path used from the admin chat, and notifies adminChatID. Super-users are excluded,
+// as on the other ban paths in this package, and so are already approved users; a user is only ever
+// actioned once: in dry mode the user is never actually removed, so without this a listed user who
+// keeps posting would be re-banned and re-announced on every single message.
+func (e *ExternalBanSource) CheckAndBan(msg bot.Message) error {
+	reason, ok := e.IsBanned(msg.From.ID)
+	if !ok {
+		return nil
+	}
+
+	if msg.From.UserName != "" && e.admin.superUsers.IsSuper(msg.From.UserName) {
+		return nil
+	}
+	if e.admin.bot.IsApprovedUser(msg.From.ID) {
+		return nil
+	}
+	if !e.markActioned(msg.From.ID) {
+		return nil
+	}
+
+	banReq := banRequest{duration: e.admin.effectiveBanDuration(bot.PermanentBanDuration), userID: msg.From.ID, chatID: e.primChatID, tbAPI: e.admin.tbAPI, dry: e.dry}
+	if err := banUserOrChannel(banReq); err != nil {
+		return fmt.Errorf("failed to ban user %d from external ban list: %w", msg.From.ID, err)
+	}
+
+	text := fmt.Sprintf("**user [%s](tg://user?id=%d) auto-banned, found on an external ban list**\n\nreason: %s\n\n",
+		escapeMarkDownV1Text(msg.From.UserName), msg.From.ID, escapeMarkDownV1Text(reason))
+	if err := e.admin.sendWithUnbanMarkup(text, "change ban", msg.From, msg.ID, e.admin.adminChatID); err != nil {
+		return fmt.Errorf("failed to notify admin chat: %w", err)
+	}
+	log.Printf("[INFO] user %q (%d) auto-banned from external ban list, reason: %q", msg.From.UserName, msg.From.ID, reason)
+	return nil
+}
+
+// markActioned reports whether userID has not yet been actioned and, if so, marks it actioned so a
+// later call for the same user returns false.
+func (e *ExternalBanSource) markActioned(userID int64) bool {
+	e.actionedMu.Lock()
+	defer e.actionedMu.Unlock()
+	if e.actioned[userID] {
+		return false
+	}
+	e.actioned[userID] = true
+	return true
+}
+
+// casBanListSource parses the CAS (Combot Anti-Spam) export format: {"ids": [123, 456], "reason": "..."}.
+// it is a minimal example of a BanListSource implementation; real deployments may prefer their own.
+type casBanListSource struct {
+	url string
+}
+
+// NewCASBanListSource creates a BanListSource for a CAS-style export endpoint
+func NewCASBanListSource(url string) BanListSource {
+	return &casBanListSource{url: url}
+}
+
+func (s *casBanListSource) URL() string { return s.url }
+
+func (s *casBanListSource) Parse(data []byte) (map[int64]string, error) {
+	var payload struct {
+		IDs    []int64 `json:"ids"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CAS export: %w", err)
+	}
+
+	reason := payload.Reason
+	if reason == "" {
+		reason = "cas ban list"
+	}
+	result := make(map[int64]string, len(payload.IDs))
+	for _, id := range payload.IDs {
+		result[id] = reason
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCasBanListSourceParse(t *testing.T) {
+	src := NewCASBanListSource("https://example.com/export.json")
+
+	entries, err := src.Parse([]byte(`{"ids": [100, 200], "reason": "known spammer"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[100] != "known spammer" || entries[200] != "known spammer" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	entries, err = src.Parse([]byte(`{"ids": [1]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[1] != "cas ban list" {
+		t.Fatalf("expected default reason, got %+v", entries)
+	}
+
+	if _, err := src.Parse([]byte(`not json`)); err == nil {
+		t.Fatal("expected an error for invalid json")
+	}
+}
+
+func TestExternalBanSourceRefreshOneUsesETagCaching(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"ids": [42], "reason": "bot net"}`))
+	}))
+	defer srv.Close()
+
+	e := NewExternalBanSource(&admin{}, 0, 0, false, NewCASBanListSource(srv.URL))
+
+	entries, err := e.refreshOne(e.sources[0])
+	if err != nil {
+		t.Fatalf("unexpected error on first fetch: %v", err)
+	}
+	if entries[42] != "bot net" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	entries, err = e.refreshOne(e.sources[0])
+	if err != nil {
+		t.Fatalf("unexpected error on cached fetch: %v", err)
+	}
+	if entries != nil {
+		t.Fatalf("expected a nil map for a 304 response, got %+v", entries)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestExternalBanSourceRefreshAllAndIsBanned(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ids": [7], "reason": "cas"}`))
+	}))
+	defer srv.Close()
+
+	e := NewExternalBanSource(&admin{}, 0, 0, false, NewCASBanListSource(srv.URL))
+
+	if err := e.refreshAll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reason, ok := e.IsBanned(7); !ok || reason != "cas" {
+		t.Fatalf("expected user 7 to be banned with reason %q, got %q, %v", "cas", reason, ok)
+	}
+	if _, ok := e.IsBanned(8); ok {
+		t.Fatal("user 8 should not be on the ban list")
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"fmt"
+
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/umputun/tg-spam/app/bot"
+)
+
+// TbAPI is the subset of the telegram-bot-api client admin and the listener depend on.
+type TbAPI interface {
+	Send(c tbapi.Chattable) (tbapi.Message, error)
+	Request(c tbapi.Chattable) (*tbapi.APIResponse, error)
+	GetChatMember(config tbapi.GetChatMemberConfig) (tbapi.ChatMember, error)
+	GetUpdatesChan(config tbapi.UpdateConfig) tbapi.UpdatesChannel
+}
+
+// Bot is the spam-detection and approved-user-list collaborator admin drives.
+type Bot interface {
+	OnMessage(msg bot.Message) bot.Response
+	UpdateSpam(msg string) error
+	UpdateHam(msg string) error
+	IsApprovedUser(userID int64) bool
+	AddApprovedUser(userID int64, userName string) error
+	RemoveApprovedUser(userID int64) error
+}
+
+// SuperUsers reports whether a username belongs to a configured super-user, exempt from moderation.
+type SuperUsers interface {
+	IsSuper(userName string) bool
+}
+
+// Locator resolves forwarded/reported messages and usernames back to their original sender, backed by
+// the bot's rolling window of recently seen messages and spam-check results. Message, Spam and
+// UserNameByID are the contract admin has always depended on; UserIDByName and Find are added here so
+// "/whois @user" and "/find" have something to resolve against.
+type Locator interface {
+	// Message finds the sender of msg among recently seen messages, used to recover the user ID that
+	// telegram strips from forwarded messages.
+	Message(msg string) (LocatorUserInfo, bool)
+	// Spam returns the last recorded spam-detection results for userID, if any.
+	Spam(userID int64) (LocatorSpamInfo, bool)
+	// UserNameByID returns the last known username for userID, or "" if unknown.
+	UserNameByID(userID int64) string
+	// UserIDByName resolves a username (without the leading "@") to a userID, for "/whois @user".
+	UserIDByName(userName string) (int64, bool)
+	// Find searches recently seen messages for a substring match, for "/find".
+	Find(substr string) []LocatorUserInfo
+}
+
+// LocatorUserInfo identifies the sender of a recently seen message
+type LocatorUserInfo struct {
+	UserID   int64
+	UserName string
+	MsgID    int
+}
+
+func (i LocatorUserInfo) String() string {
+	return fmt.Sprintf("{%d %s msg:%d}", i.UserID, i.UserName, i.MsgID)
+}
+
+// LocatorSpamInfo is the last recorded spam-detection outcome for a user
+type LocatorSpamInfo struct {
+	Checks []fmt.Stringer
+}
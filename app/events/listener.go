@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramListener polls Telegram for updates and dispatches each one to admin: callbacks from the
+// admin-chat inline keyboards, forwarded/command messages from the admin chat, and primary-chat
+// messages (commands like "/report", and anything admin's external ban source wants to see).
+type TelegramListener struct {
+	TbAPI      TbAPI
+	Bot        Bot
+	Locator    Locator
+	SuperUsers SuperUsers
+	Settings   SettingsStore
+
+	PrimChatID   int64
+	AdminChatID  int64
+	TrainingMode bool
+	Dry          bool
+
+	// ExternalBan, if set, is attached to admin and started once Do begins polling.
+	ExternalBan *ExternalBanSource
+
+	admin *admin
+}
+
+// Do starts polling Telegram for updates and dispatching them until ctx is canceled.
+func (l *TelegramListener) Do(ctx context.Context) error {
+	l.admin = newAdmin(l.TbAPI, l.Bot, l.Locator, l.SuperUsers, l.PrimChatID, l.AdminChatID, l.TrainingMode, l.Dry, l.Settings)
+	if l.ExternalBan != nil {
+		l.admin.UseExternalBanSource(ctx, l.ExternalBan)
+	}
+
+	updates := l.TbAPI.GetUpdatesChan(tbapi.NewUpdate(0))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			l.dispatch(update)
+		}
+	}
+}
+
+// dispatch routes a single update to the admin handler responsible for the chat/update kind it came from.
+func (l *TelegramListener) dispatch(update tbapi.Update) {
+	switch {
+	case update.CallbackQuery != nil:
+		if err := l.admin.InlineCallbackHandler(update.CallbackQuery); err != nil {
+			log.Printf("[WARN] failed to process callback: %v", err)
+		}
+	case update.Message != nil && update.Message.Chat.ID == l.AdminChatID:
+		if err := l.admin.MsgHandler(update); err != nil {
+			log.Printf("[WARN] failed to process admin chat message: %v", err)
+		}
+	case update.Message != nil && update.Message.Chat.ID == l.PrimChatID:
+		if err := l.admin.HandleUpdate(update); err != nil {
+			log.Printf("[DEBUG] primary chat update not actioned: %v", err)
+		}
+	}
+}